@@ -4,23 +4,43 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"log"
 	"math"
 	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"git.dolansoft.org/lorenz/go-zfs/ioctl"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gopkg.in/yaml.v3"
 )
 
 var (
 	listenAddr = flag.String("listen-addr", ":9700", "Address the ZFS exporter should listen on")
 	vdevDepth  = flag.Int("depth", 1, "Depth of the vdev tree to report on. 0 is the pool, 1 is top level vdevs, 2 is devices too")
 	fullPath   = flag.Bool("fullpath", false, "Report the full path of disks")
+
+	format     = flag.String("format", "prometheus", "Exposition format: 'prometheus' (HTTP /metrics) or 'influxdb' (line protocol on stdout, for Telegraf's inputs.exec/inputs.execd)")
+	influxMode = flag.String("influxdb-mode", "exec", "When -format=influxdb: 'exec' collects once and exits, 'execd' collects once per newline read from stdin and runs until stdin closes")
+
+	noHistograms        = flag.Bool("no-histograms", false, "Don't report the vdev extended-stats latency/size histograms at all, to cut cardinality")
+	sumHistogramBuckets = flag.Bool("sum-histogram-buckets", false, "Collapse the 37-bucket vdev latency histograms into a handful of summed buckets instead of the full histogram, to cut cardinality")
+
+	configPath = flag.String("config", "", "Path to a YAML config file controlling which pools/vdevs/extended-stats get reported and how their tags are relabeled. Reloaded without a restart on SIGHUP.")
+
+	collectTimeout = flag.Duration("collect-timeout", 30*time.Second, "Per-pool timeout for collecting stats. A pool that doesn't respond in time is reported via zfs_pool_up=0 and zfs_pool_collect_errors_total instead of hanging or failing the whole scrape.")
 )
 
 type stat struct {
@@ -31,6 +51,160 @@ type stat struct {
 	desc      *prometheus.Desc
 }
 
+// filterConfig controls which pools, vdevs, and extended-stats get
+// reported, and how their tags/labels are rewritten. It's loaded from
+// -config (YAML) at startup; see loadFilterConfig and
+// watchFilterConfigReload. A nil *filterConfig (no -config given)
+// allows everything and relabels nothing.
+type filterConfig struct {
+	IncludePools []string `yaml:"include_pools"`
+	ExcludePools []string `yaml:"exclude_pools"`
+	IncludeVdevs []string `yaml:"include_vdevs"`
+	ExcludeVdevs []string `yaml:"exclude_vdevs"`
+	IncludePaths []string `yaml:"include_paths"`
+	ExcludePaths []string `yaml:"exclude_paths"`
+
+	// ExcludeExtStats matches against the raw kernel extended-stat
+	// name (eg "vdev_async_r_lat_histo"), letting operators drop
+	// specific histograms/queue stats without dropping a whole vdev.
+	ExcludeExtStats []string `yaml:"exclude_ext_stats"`
+
+	// Relabel renames tag/label keys, eg {"zpool": "pool"}.
+	// StaticLabels are attached to every record unconditionally, eg
+	// {"datacenter": "dc1"}. Both only apply to the InfluxDB output:
+	// in Prometheus mode, each record's Desc already has a fixed
+	// label name set from registration time, so per-config renames
+	// or additions aren't possible without re-registering Descs,
+	// which we don't do. Prometheus users rename/add labels with
+	// relabel_configs in their scrape config instead.
+	Relabel      map[string]string `yaml:"relabel"`
+	StaticLabels map[string]string `yaml:"static_labels"`
+}
+
+// currentFilter holds the active *filterConfig (nil if -config was
+// never given, or before the first successful load).
+var currentFilter atomic.Value
+
+func currentFilterConfig() *filterConfig {
+	fc, _ := currentFilter.Load().(*filterConfig)
+	return fc
+}
+
+func loadFilterConfig(path string) (*filterConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fc filterConfig
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &fc, nil
+}
+
+// watchFilterConfigReload reloads path into currentFilter every time
+// the process receives SIGHUP, so that scrape-config-style filtering
+// changes don't need a restart. A bad config on reload is logged and
+// ignored, keeping the previous (working) config live.
+func watchFilterConfigReload(path string) {
+	if path == "" {
+		return
+	}
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			fc, err := loadFilterConfig(path)
+			if err != nil {
+				log.Printf("config: reload of %s failed, keeping previous config: %v", path, err)
+				continue
+			}
+			currentFilter.Store(fc)
+			log.Printf("config: reloaded %s", path)
+		}
+	}()
+}
+
+// matchesAny reports whether s matches any of the shell-style globs
+// in patterns. Matching is done with globMatch rather than
+// filepath.Match because vdev names (see vdevName) use "/" as an
+// ordinary separator between nested mirror/raidz levels, not as a
+// path boundary that "*" should refuse to cross.
+func matchesAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if globMatch(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch reports whether s matches the shell-style glob pattern,
+// treating "/" as an ordinary character. It supports the same "*",
+// "?" and "[...]" syntax as filepath.Match, without filepath.Match's
+// special-casing of the path separator.
+func globMatch(pattern, s string) bool {
+	ok, err := filepath.Match(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(s, "/", "\x00"))
+	return err == nil && ok
+}
+
+// allowed applies the usual include/exclude glob convention: exclude
+// wins outright, and an empty include list means "everything not
+// excluded".
+func allowed(include, exclude []string, s string) bool {
+	if matchesAny(exclude, s) {
+		return false
+	}
+	return len(include) == 0 || matchesAny(include, s)
+}
+
+// allowRecord reports whether r's pool/vdev/path tags pass f's
+// include/exclude globs. A nil f allows everything.
+func (f *filterConfig) allowRecord(r metricRecord) bool {
+	if f == nil {
+		return true
+	}
+	if !allowed(f.IncludePools, f.ExcludePools, r.tags["zpool"]) {
+		return false
+	}
+	if !allowed(f.IncludeVdevs, f.ExcludeVdevs, r.tags["vdev"]) {
+		return false
+	}
+	return allowed(f.IncludePaths, f.ExcludePaths, r.tags["path"])
+}
+
+// excludeExtStat reports whether f's config says to drop the
+// extended stat named by the raw kernel name (eg
+// "vdev_async_r_lat_histo"). A nil f excludes nothing.
+func (f *filterConfig) excludeExtStat(name string) bool {
+	return f != nil && matchesAny(f.ExcludeExtStats, name)
+}
+
+// relabel applies f's tag renames and static labels to a copy of r's
+// tags, for the InfluxDB output; see the filterConfig doc comment for
+// why this doesn't apply to Prometheus mode. A nil f, or one with no
+// renames or static labels configured, is a no-op.
+func (f *filterConfig) relabel(r metricRecord) metricRecord {
+	if f == nil || (len(f.Relabel) == 0 && len(f.StaticLabels) == 0) {
+		return r
+	}
+	tags := make(map[string]string, len(r.tags)+len(f.StaticLabels))
+	for k, v := range r.tags {
+		if nk, ok := f.Relabel[k]; ok {
+			k = nk
+		}
+		tags[k] = v
+	}
+	for k, v := range f.StaticLabels {
+		tags[k] = v
+	}
+	r.tags = tags
+	return r
+}
+
 var (
 	zioNames = []string{"null", "read", "write", "free", "claim", "ioctl"}
 )
@@ -73,9 +247,11 @@ var vdevStats = []stat{
 	{n: "physical_capacity_bytes", d: "physical capacity"},
 }
 
-// <cks>: this is struct pool_scan_stat.
-// TODO: should we create a derived statistic for the last scan duration,
-// when the scan state is 2? It's not necessarily easy to get it otherwise.
+// <cks>: this is struct pool_scan_stat. OpenZFS populates this both
+// at the pool root (the traditional place) and, more recently, on
+// individual top-level and child vdevs, so that per-disk
+// resilver/scrub/rebuild progress can be told apart. We report both;
+// see vdevScanStats below for the per-vdev version.
 var scanStats = []stat{
 	{n: "scan_func", d: "Pool scan function: 0 none, 1 scrub, 2 resilver, 3 rebuild (maybe)"},
 	{n: "scan_state", d: "Pool scan state: 0 none, 1 scanning, 2 finished, 3 cancelled"},
@@ -95,6 +271,12 @@ var scanStats = []stat{
 	{n: "scan_issued_bytes", d: "Total bytes checked by scanner"},
 }
 
+// vdevScanStats mirrors scanStats (same fields, same indices into the
+// raw scan_stats array), but with zfs_vdev_scan_* Descs labelled by
+// vdev/zpool/path instead of just zpool, for vdevs that carry their
+// own scan_stats. It's built from scanStats in init().
+var vdevScanStats []stat
+
 var (
 	extendedStatsLabels = []string{"type", "vdev", "zpool", "path"}
 )
@@ -124,8 +306,99 @@ var (
 	// which I believe may include adding and removing devices to
 	// mirror vdevs.
 	poolConfigTxg = prometheus.NewDesc("zfs_pool_config_txg", "ZFS pool configuration load or change txg", []string{"zpool"}, nil)
+
+	// poolScanDuration and vdevScanDuration are derived stats: the
+	// wall-clock time a finished scan (scrub/resilver/rebuild) took,
+	// computed from scan_end_time_seconds - scan_start_time_seconds
+	// when scan_state is 2 (finished). This was the TODO this file
+	// used to carry about pool-level scan duration; it now covers
+	// per-vdev scan_stats too.
+	poolScanDuration = prometheus.NewDesc("zfs_pool_scan_duration_seconds", "Wall-clock duration of the most recently finished pool scan (scrub/resilver/rebuild)", []string{"zpool"}, nil)
+	vdevScanDuration = prometheus.NewDesc("zfs_vdev_scan_duration_seconds", "Wall-clock duration of the most recently finished scan (scrub/resilver/rebuild) on this vdev", []string{"vdev", "zpool", "path"}, nil)
+
+	// poolUp, poolCollectErrors, and scrapeDuration describe the
+	// collection itself rather than anything ZFS reports, so that a
+	// pool that hangs or errors shows up as a metric instead of
+	// taking down the whole scrape; see zfsCollector.Collect.
+	poolUp            = prometheus.NewDesc("zfs_pool_up", "Whether this pool's stats were collected successfully during the last scrape (1) or not (0, eg on timeout or ioctl error)", []string{"zpool"}, nil)
+	poolCollectErrors = prometheus.NewDesc("zfs_pool_collect_errors_total", "Count of failed or timed-out stat collections for this pool since the exporter started", []string{"zpool"}, nil)
+	scrapeDuration    = prometheus.NewDesc("zfs_scrape_duration_seconds", "Time spent collecting this pool's stats during the last scrape", []string{"zpool"}, nil)
+
+	// vdevLatencyAverage is a derived stat: the weighted mean of one
+	// of the latency histograms below, so operators get a single
+	// number per queue class without needing Prometheus-side
+	// histogram_quantile() over high-cardinality buckets. See
+	// latencyAverageClass.
+	vdevLatencyAverage = prometheus.NewDesc("zfs_vdev_latency_average_seconds", "Average (mean) latency for this queue class, derived from its latency histogram the same way 'zpool iostat -l' does", []string{"vdev", "zpool", "path", "class", "op"}, nil)
+
+	// vdevHistoBucketSum and vdevHistoCount back -sum-histogram-buckets:
+	// instead of the full 37-bucket histogram, reportVdevStats emits
+	// these few summed buckets, trading precision for far lower
+	// series cardinality on hosts with many disks.
+	vdevHistoBucketSum = prometheus.NewDesc("zfs_vdev_histogram_bucket", "Summed histogram sample count for one of a handful of coarse latency buckets, reported instead of the full histogram when -sum-histogram-buckets is set", append(append([]string{}, extendedStatsLabels...), "stat", "bucket"), nil)
+	vdevHistoCount     = prometheus.NewDesc("zfs_vdev_histogram_count", "Total histogram sample count, alongside zfs_vdev_histogram_bucket when -sum-histogram-buckets is set", append(append([]string{}, extendedStatsLabels...), "stat"), nil)
 )
 
+// histoBucketBounds are the fixed, cumulative cutoffs
+// -sum-histogram-buckets collapses a 37-bucket latency histogram
+// into, the same way the upstream Telegraf collector keeps series
+// cardinality manageable on hosts with many disks. Anything past the
+// last bound is reported as the separate "gt_1s" bucket.
+var histoBucketBounds = []struct {
+	label string
+	upper float64 // seconds
+}{
+	{"le_1us", 1e-6},
+	{"le_10us", 1e-5},
+	{"le_100us", 1e-4},
+	{"le_1ms", 1e-3},
+	{"le_10ms", 1e-2},
+	{"le_100ms", 1e-1},
+	{"le_1s", 1},
+}
+
+// latencyAverageClass maps the latency-histogram extended stats to
+// the (class, op) breakdown 'zpool iostat -l' uses: total, disk,
+// syncq and asyncq queue latency, each split by read/write, plus
+// scrub latency (which isn't split by read/write).
+var latencyAverageClass = map[string]struct{ class, op string }{
+	"vdev_tot_r_lat_histo":   {"total", "read"},
+	"vdev_tot_w_lat_histo":   {"total", "write"},
+	"vdev_disk_r_lat_histo":  {"disk", "read"},
+	"vdev_disk_w_lat_histo":  {"disk", "write"},
+	"vdev_sync_r_lat_histo":  {"syncq", "read"},
+	"vdev_sync_w_lat_histo":  {"syncq", "write"},
+	"vdev_async_r_lat_histo": {"asyncq", "read"},
+	"vdev_async_w_lat_histo": {"asyncq", "write"},
+	"vdev_scrub_histo":       {"scrub", ""},
+}
+
+// descNames maps the package-level Descs above back to the metric
+// name they were created with. *prometheus.Desc doesn't expose its
+// fqName, but the InfluxDB output needs the name again to use as a
+// measurement/field name, so we keep a copy here instead of parsing
+// Desc.String().
+var descNames = map[*prometheus.Desc]string{
+	activeQueueLength:  "zfs_vdev_queue_active_length",
+	pendingQueueLength: "zfs_vdev_queue_pending_length",
+	queueLatency:       "zfs_vdev_queue_latency",
+	zioLatencyTotal:    "zfs_vdev_zio_latency_total",
+	zioLatencyDisk:     "zfs_vdev_latency_disk",
+	individualIOSize:   "zfs_vdev_io_size_individual",
+	aggregatedIOSize:   "zfs_vdev_io_size_aggregated",
+	poolLoadTime:       "zfs_pool_load_time_seconds",
+	poolErrors:         "zfs_pool_errors",
+	poolChildren:       "zfs_pool_vdevs",
+	vdevChildren:       "zfs_vdev_children",
+	vdevNparity:        "zfs_vdev_nparity",
+	poolConfigTxg:      "zfs_pool_config_txg",
+	vdevLatencyAverage: "zfs_vdev_latency_average_seconds",
+	vdevHistoBucketSum: "zfs_vdev_histogram_bucket",
+	vdevHistoCount:     "zfs_vdev_histogram_count",
+	poolScanDuration:   "zfs_pool_scan_duration_seconds",
+	vdevScanDuration:   "zfs_vdev_scan_duration_seconds",
+}
+
 type extStat struct {
 	name  string
 	desc  *prometheus.Desc
@@ -195,12 +468,65 @@ func init() {
 	for i, s := range scanStats {
 		scanStats[i].desc = prometheus.NewDesc("zfs_pool_"+s.n, "ZFS Pool Scan "+s.d, []string{"zpool"}, nil)
 	}
+	vdevScanStats = make([]stat, len(scanStats))
+	for i, s := range scanStats {
+		vdevScanStats[i] = stat{n: s.n, d: s.d}
+		vdevScanStats[i].desc = prometheus.NewDesc("zfs_vdev_"+s.n, "ZFS VDev Scan "+s.d, []string{"vdev", "zpool", "path"}, nil)
+	}
 	extStatsMap = make(map[string]extStat)
 	for _, v := range extStats {
 		extStatsMap[v.name] = v
 	}
 }
 
+// fieldValue is one named value inside a metricRecord, carrying
+// enough of its Prometheus identity (Desc and value type) to be
+// rendered as a prometheus.Metric as well as an InfluxDB field.
+//
+// fractional marks values that are genuinely fractional (eg the
+// latency/duration gauges, which are seconds as a float64) rather
+// than ZFS's usual integer counters and gauges, so that InfluxDB
+// rendering doesn't truncate them down to the int64 "i" suffix; see
+// influxLine.
+type fieldValue struct {
+	value      float64
+	desc       *prometheus.Desc
+	vtype      prometheus.ValueType
+	fractional bool
+}
+
+// metricRecord is the format-neutral intermediate produced while
+// walking a pool's vdev tree: a measurement name, a tag set, and one
+// or more named field values that all share that tag set. Prometheus
+// rendering turns each field back into its own prometheus.Metric
+// (using fieldValue.desc); InfluxDB rendering turns the whole record
+// into one line-protocol line with all the fields on it.
+//
+// labelValues holds the tag values in the order the fields' Desc(s)
+// expect them; every field in a record must share the same label
+// order, which is why variant stats (eg ops/read vs ops/write) get
+// their own record instead of being folded into a shared one.
+type metricRecord struct {
+	measurement string
+	tags        map[string]string
+	labelValues []string
+	fields      map[string]fieldValue
+}
+
+// histoRecord is the histogram equivalent of metricRecord. It is kept
+// separate because a histogram's Prometheus rendering
+// (MustNewConstHistogram) and its eventual InfluxDB rendering don't
+// share shape with plain fields.
+type histoRecord struct {
+	measurement string
+	tags        map[string]string
+	labelValues []string
+	desc        *prometheus.Desc
+	count       uint64
+	sum         float64
+	buckets     map[float64]uint64
+}
+
 type zfsCollector struct{}
 
 func (c *zfsCollector) Describe(ch chan<- *prometheus.Desc) {
@@ -213,6 +539,11 @@ func (c *zfsCollector) Describe(ch chan<- *prometheus.Desc) {
 	for _, s := range scanStats {
 		ch <- s.desc
 	}
+	for _, s := range vdevScanStats {
+		ch <- s.desc
+	}
+	ch <- poolScanDuration
+	ch <- vdevScanDuration
 	ch <- activeQueueLength
 	ch <- pendingQueueLength
 	ch <- queueLatency
@@ -226,6 +557,12 @@ func (c *zfsCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- vdevChildren
 	ch <- vdevNparity
 	ch <- poolConfigTxg
+	ch <- vdevLatencyAverage
+	ch <- vdevHistoBucketSum
+	ch <- vdevHistoCount
+	ch <- poolUp
+	ch <- poolCollectErrors
+	ch <- scrapeDuration
 }
 
 // vdevName generates the name for the vdev= label.
@@ -292,18 +629,40 @@ func vdevName(parent string, vdev map[string]interface{}) string {
 // one. Normally this is physical disks. Otherwise, the vdev type is
 // implicit in its name, eg "mirror-0" is a mirror. Physical disks
 // have the vdev name of their parent vdev.
-func reportVdevStats(poolName, vdevName string, vdev map[string]interface{}, ch chan<- prometheus.Metric) {
+//
+// Data points are handed to emit/emitHisto as metricRecord/histoRecord
+// values instead of being pushed directly onto a Prometheus channel,
+// so that the same walk can be rendered as either Prometheus metrics
+// or InfluxDB line-protocol lines; see collectPoolWithTimeout and
+// runInfluxDBCycle.
+func reportVdevStats(poolName, vdevName string, vdev map[string]interface{}, fc *filterConfig, emit func(metricRecord), emitHisto func(histoRecord)) {
 	// Because disk IO stats bubble up from the individual disk devices,
 	// we want to know how many children there are in a given vdev in
 	// some situations. (This is an approximation in some situations,
 	// but usually the vdev hierarchy is flat, without spares and so
 	// on to make the count of children in top-level vdevs inaccurate.)
 	if chld, ok := vdev["children"]; ok {
-		ch <- prometheus.MustNewConstMetric(vdevChildren, prometheus.GaugeValue, float64(len(chld.([]map[string]interface{}))), vdevName, poolName)
+		name := descNames[vdevChildren]
+		emit(metricRecord{
+			measurement: name,
+			tags:        map[string]string{"vdev": vdevName, "zpool": poolName},
+			labelValues: []string{vdevName, poolName},
+			fields: map[string]fieldValue{
+				name: {value: float64(len(chld.([]map[string]interface{}))), desc: vdevChildren, vtype: prometheus.GaugeValue},
+			},
+		})
 	}
 
 	if nparity, ok := vdev["nparity"]; ok {
-		ch <- prometheus.MustNewConstMetric(vdevNparity, prometheus.GaugeValue, float64(nparity.(uint64)), vdevName, poolName)
+		name := descNames[vdevNparity]
+		emit(metricRecord{
+			measurement: name,
+			tags:        map[string]string{"vdev": vdevName, "zpool": poolName},
+			labelValues: []string{vdevName, poolName},
+			fields: map[string]fieldValue{
+				name: {value: float64(nparity.(uint64)), desc: vdevNparity, vtype: prometheus.GaugeValue},
+			},
+		})
 	}
 
 	rawStats := vdev["vdev_stats"].([]uint64)
@@ -315,6 +674,17 @@ func reportVdevStats(poolName, vdevName string, vdev map[string]interface{}, ch
 		path = filepath.Base(path)
 	}
 
+	// The plain (non-variant) stats all share the same tags, so we
+	// fold them into a single record; this is what lets the
+	// InfluxDB output combine them onto one line-protocol line
+	// instead of one line per stat.
+	plain := metricRecord{
+		measurement: "zfs_vdev_stats",
+		tags:        map[string]string{"vdev": vdevName, "zpool": poolName, "path": path},
+		labelValues: []string{vdevName, poolName, path},
+		fields:      map[string]fieldValue{},
+	}
+
 	// vdevStats entries with variants actually cover (and
 	// consume) multiple raw stats, forcing us to avoid
 	// the simple iteration approach.
@@ -328,25 +698,57 @@ func reportVdevStats(poolName, vdevName string, vdev map[string]interface{}, ch
 			continue
 		}
 		if len(s.variants) == 0 {
-			ch <- prometheus.MustNewConstMetric(s.desc, prometheus.UntypedValue, float64(rawStats[i]), vdevName, poolName, path)
+			plain.fields[s.n] = fieldValue{value: float64(rawStats[i]), desc: s.desc, vtype: prometheus.UntypedValue}
 			i++
 		} else {
 			for _, v := range s.variants {
-				ch <- prometheus.MustNewConstMetric(s.desc, prometheus.UntypedValue, float64(rawStats[i]), vdevName, poolName, path, v)
+				emit(metricRecord{
+					measurement: "zfs_vdev_" + s.n,
+					tags:        map[string]string{"vdev": vdevName, "zpool": poolName, "path": path, s.dimension: v},
+					labelValues: []string{vdevName, poolName, path, v},
+					fields: map[string]fieldValue{
+						s.n: {value: float64(rawStats[i]), desc: s.desc, vtype: prometheus.UntypedValue},
+					},
+				})
 				i++
 			}
 		}
 	}
-	extended_stats := vdev["vdev_stats_ex"].(map[string]interface{})
-	for name, val := range extended_stats {
+	if len(plain.fields) > 0 {
+		emit(plain)
+	}
+
+	extendedStats := vdev["vdev_stats_ex"].(map[string]interface{})
+	for name, val := range extendedStats {
 		statMeta := extStatsMap[name]
 		if statMeta.name == "" {
 			continue
 		}
+		if fc.excludeExtStat(name) {
+			continue
+		}
+		metricName := descNames[statMeta.desc]
+		tags := map[string]string{"type": statMeta.label, "vdev": vdevName, "zpool": poolName, "path": path}
+		labelValues := []string{statMeta.label, vdevName, poolName, path}
 		if scalar, ok := val.(uint64); ok {
-			ch <- prometheus.MustNewConstMetric(statMeta.desc, prometheus.GaugeValue, float64(scalar), statMeta.label, vdevName, poolName, path)
+			emit(metricRecord{
+				measurement: metricName,
+				tags:        tags,
+				labelValues: labelValues,
+				fields: map[string]fieldValue{
+					metricName: {value: float64(scalar), desc: statMeta.desc, vtype: prometheus.GaugeValue},
+				},
+			})
 		} else if histo, ok := val.([]uint64); ok {
 			buckets := make(map[float64]uint64)
+			// summed is only built for the 37-bucket (ns)
+			// latency histograms; the fixed bounds above are
+			// time cutoffs and don't mean anything for the
+			// queue-length/IO-size histograms.
+			var summed map[string]uint64
+			if *sumHistogramBuckets && !*noHistograms && len(histo) == 37 {
+				summed = make(map[string]uint64, len(histoBucketBounds))
+			}
 			var count uint64
 			var acc float64
 			var divisor float64 = 1.0
@@ -355,7 +757,15 @@ func reportVdevStats(poolName, vdevName string, vdev map[string]interface{}, ch
 			}
 			for i, v := range histo {
 				count += v
-				buckets[math.Exp2(float64(i))/divisor] = count
+				upper := math.Exp2(float64(i)) / divisor
+				buckets[upper] = count
+				if summed != nil {
+					for _, b := range histoBucketBounds {
+						if upper <= b.upper {
+							summed[b.label] = count
+						}
+					}
+				}
 				midpoint := (1 << i) + ((1 << i) / 2)
 				// This mimics the calculation that
 				// 'zpool iostat' does. We can't do
@@ -366,14 +776,120 @@ func reportVdevStats(poolName, vdevName string, vdev map[string]interface{}, ch
 			// <cks>: the upstream version punts on an
 			// accumulated value (and calls the count
 			// 'acc', just to fool you).
-			ch <- prometheus.MustNewConstHistogram(statMeta.desc, count, acc/divisor, buckets, statMeta.label, vdevName, poolName, path)
+			sum := acc / divisor
+			if lc, ok := latencyAverageClass[name]; ok {
+				var mean float64
+				if count > 0 {
+					mean = sum / float64(count)
+				}
+				avgTags := map[string]string{"vdev": vdevName, "zpool": poolName, "path": path, "class": lc.class, "op": lc.op}
+				emit(metricRecord{
+					measurement: descNames[vdevLatencyAverage],
+					tags:        avgTags,
+					labelValues: []string{vdevName, poolName, path, lc.class, lc.op},
+					fields: map[string]fieldValue{
+						descNames[vdevLatencyAverage]: {value: mean, desc: vdevLatencyAverage, vtype: prometheus.GaugeValue, fractional: true},
+					},
+				})
+			}
+			if *noHistograms {
+				continue
+			}
+			if summed != nil {
+				summed["gt_1s"] = count - summed["le_1s"]
+				allBuckets := make([]struct {
+					label string
+					upper float64
+				}, 0, len(histoBucketBounds)+1)
+				allBuckets = append(allBuckets, histoBucketBounds...)
+				allBuckets = append(allBuckets, struct {
+					label string
+					upper float64
+				}{"gt_1s", math.Inf(1)})
+				for _, b := range allBuckets {
+					bTags := map[string]string{"type": statMeta.label, "vdev": vdevName, "zpool": poolName, "path": path, "stat": metricName, "bucket": b.label}
+					emit(metricRecord{
+						measurement: descNames[vdevHistoBucketSum],
+						tags:        bTags,
+						labelValues: append(append(append([]string{}, labelValues...), metricName), b.label),
+						fields: map[string]fieldValue{
+							descNames[vdevHistoBucketSum]: {value: float64(summed[b.label]), desc: vdevHistoBucketSum, vtype: prometheus.GaugeValue},
+						},
+					})
+				}
+				countTags := map[string]string{"type": statMeta.label, "vdev": vdevName, "zpool": poolName, "path": path, "stat": metricName}
+				emit(metricRecord{
+					measurement: descNames[vdevHistoCount],
+					tags:        countTags,
+					labelValues: append(append([]string{}, labelValues...), metricName),
+					fields: map[string]fieldValue{
+						descNames[vdevHistoCount]: {value: float64(count), desc: vdevHistoCount, vtype: prometheus.GaugeValue},
+					},
+				})
+				continue
+			}
+			emitHisto(histoRecord{
+				measurement: metricName,
+				tags:        tags,
+				labelValues: labelValues,
+				desc:        statMeta.desc,
+				count:       count,
+				sum:         sum,
+				buckets:     buckets,
+			})
 		} else {
 			log.Fatalf("invalid type encountered: %T", val)
 		}
 	}
+
+	// OpenZFS also populates scan_stats (resilver/scrub/rebuild
+	// progress) on top-level and child vdevs, not just the pool
+	// root; report it here the same way collectOnePool reports the
+	// pool-level scan_stats. The root vdev's scan_stats is the same
+	// array collectOnePool already reports as zfs_pool_scan /
+	// zfs_pool_scan_duration_seconds, so skip it here to avoid
+	// reporting the pool-level scan under a second, vdev-shaped name.
+	if vdevName != "root" && vdev["scan_stats"] != nil {
+		rawScan := vdev["scan_stats"].([]uint64)
+		rec := metricRecord{
+			measurement: "zfs_vdev_scan",
+			tags:        map[string]string{"vdev": vdevName, "zpool": poolName, "path": path},
+			labelValues: []string{vdevName, poolName, path},
+			fields:      map[string]fieldValue{},
+		}
+		for i, s := range vdevScanStats {
+			if i >= len(rawScan) {
+				break
+			}
+			rec.fields[s.n] = fieldValue{value: float64(rawScan[i]), desc: s.desc, vtype: prometheus.GaugeValue}
+		}
+		emit(rec)
+		if dur, ok := scanDurationSeconds(rawScan); ok {
+			emit(metricRecord{
+				measurement: descNames[vdevScanDuration],
+				tags:        rec.tags,
+				labelValues: rec.labelValues,
+				fields: map[string]fieldValue{
+					descNames[vdevScanDuration]: {value: dur, desc: vdevScanDuration, vtype: prometheus.GaugeValue, fractional: true},
+				},
+			})
+		}
+	}
 }
 
-func descendVdev(poolName, parent string, vdev map[string]interface{}, ch chan<- prometheus.Metric) {
+// scanDurationSeconds computes the wall-clock duration of a finished
+// scan (scan_state == 2) from a raw scan_stats array, using the same
+// field indices as scanStats/vdevScanStats (scan_state, then
+// scan_start_time_seconds, then scan_end_time_seconds). It reports ok
+// == false if the scan isn't finished or the array is too short.
+func scanDurationSeconds(rawScan []uint64) (seconds float64, ok bool) {
+	if len(rawScan) < 4 || rawScan[1] != 2 {
+		return 0, false
+	}
+	return float64(rawScan[3]) - float64(rawScan[2]), true
+}
+
+func descendVdev(poolName, parent string, vdev map[string]interface{}, fc *filterConfig, emit func(metricRecord), emitHisto func(histoRecord)) {
 	chld := vdev["children"]
 	if chld == nil {
 		return
@@ -381,58 +897,357 @@ func descendVdev(poolName, parent string, vdev map[string]interface{}, ch chan<-
 	kids := chld.([]map[string]interface{})
 	for _, v := range kids {
 		vdn := vdevName(parent, v)
-		reportVdevStats(poolName, vdn, v, ch)
-		descendVdev(poolName, vdn, v, ch)
+		reportVdevStats(poolName, vdn, v, fc, emit, emitHisto)
+		descendVdev(poolName, vdn, v, fc, emit, emitHisto)
 	}
 }
 
+// collectOnePool emits one already-fetched pool's metrics: the
+// pool-level stats, the vdev walk down to -depth, and scan stats. It's the
+// shared body behind collectPools's sequential loop (used by the
+// InfluxDB output path) and zfsCollector.Collect's per-pool
+// goroutines (used by the Prometheus path).
+func collectOnePool(poolName string, stats map[string]interface{}, fc *filterConfig, emit func(metricRecord), emitHisto func(histoRecord)) {
+	// TODO: should the number of children be reported as
+	// a separate metric? Should we report the import
+	// time?
+	// children := stats["vdev_children"].(uint64)
+	guid := strconv.FormatUint(stats["pool_guid"].(uint64), 10)
+	ltimes := stats["initial_load_time"].([]uint64)
+
+	loadTimeName := descNames[poolLoadTime]
+	emit(metricRecord{
+		measurement: loadTimeName,
+		tags:        map[string]string{"zpool": poolName, "guid": guid},
+		labelValues: []string{poolName, guid},
+		fields: map[string]fieldValue{
+			loadTimeName: {value: float64(ltimes[0]), desc: poolLoadTime, vtype: prometheus.GaugeValue},
+		},
+	})
+	errorsName := descNames[poolErrors]
+	emit(metricRecord{
+		measurement: errorsName,
+		tags:        map[string]string{"zpool": poolName, "guid": guid},
+		labelValues: []string{poolName, guid},
+		fields: map[string]fieldValue{
+			errorsName: {value: float64(stats["error_count"].(uint64)), desc: poolErrors, vtype: prometheus.GaugeValue},
+		},
+	})
+	childrenName := descNames[poolChildren]
+	emit(metricRecord{
+		measurement: childrenName,
+		tags:        map[string]string{"zpool": poolName, "guid": guid},
+		labelValues: []string{poolName, guid},
+		fields: map[string]fieldValue{
+			childrenName: {value: float64(stats["vdev_children"].(uint64)), desc: poolChildren, vtype: prometheus.GaugeValue},
+		},
+	})
+	txgName := descNames[poolConfigTxg]
+	emit(metricRecord{
+		measurement: txgName,
+		tags:        map[string]string{"zpool": poolName},
+		labelValues: []string{poolName},
+		fields: map[string]fieldValue{
+			txgName: {value: float64(stats["txg"].(uint64)), desc: poolConfigTxg, vtype: prometheus.GaugeValue},
+		},
+	})
+
+	vdevTree := stats["vdev_tree"].(map[string]interface{})
+	vdevs := vdevTree["children"].([]map[string]interface{})
+	reportVdevStats(poolName, "root", vdevTree, fc, emit, emitHisto)
+	if *vdevDepth > 0 {
+		for _, vdev := range vdevs {
+			vdn := vdevName("", vdev)
+			reportVdevStats(poolName, vdn, vdev, fc, emit, emitHisto)
+			if *vdevDepth > 1 {
+				descendVdev(poolName, vdn, vdev, fc, emit, emitHisto)
+			}
+		}
+	}
+
+	// Report pool scan stats.
+	if vdevTree["scan_stats"] != nil {
+		rawStats := vdevTree["scan_stats"].([]uint64)
+		rec := metricRecord{
+			measurement: "zfs_pool_scan",
+			tags:        map[string]string{"zpool": poolName},
+			labelValues: []string{poolName},
+			fields:      map[string]fieldValue{},
+		}
+		for i, s := range scanStats {
+			if i >= len(rawStats) {
+				break
+			}
+			// We know for sure that these are all gauges.
+			rec.fields[s.n] = fieldValue{value: float64(rawStats[i]), desc: s.desc, vtype: prometheus.GaugeValue}
+		}
+		emit(rec)
+		if dur, ok := scanDurationSeconds(rawStats); ok {
+			emit(metricRecord{
+				measurement: descNames[poolScanDuration],
+				tags:        map[string]string{"zpool": poolName},
+				labelValues: []string{poolName},
+				fields: map[string]fieldValue{
+					descNames[poolScanDuration]: {value: dur, desc: poolScanDuration, vtype: prometheus.GaugeValue, fractional: true},
+				},
+			})
+		}
+	}
+}
+
+// collectPools fetches and walks every imported pool's vdev tree
+// once, sequentially, handing every data point to emit or emitHisto.
+// It's used by the InfluxDB output path (runInfluxDBCycle), which has
+// no Prometheus channel to isolate per-pool goroutines around; the
+// Prometheus path instead uses zfsCollector.Collect, which collects
+// pools concurrently with a per-pool timeout (see collectPoolWithTimeout).
+//
+// Each pool's stats are still fetched with the same -collect-timeout
+// deadline as the Prometheus path (see poolStatsWithTimeout), just
+// sequentially rather than concurrently: a hung pool would otherwise
+// hang the whole -format=influxdb process (in -influxdb-mode=execd,
+// forever, since nothing else ever drains stdin again). A pool that
+// times out or errors is logged and skipped rather than aborting the
+// rest of the cycle.
+func collectPools(fc *filterConfig, emit func(metricRecord), emitHisto func(histoRecord)) error {
+	pools, err := ioctl.PoolConfigs()
+	if err != nil {
+		return err
+	}
+	for poolName := range pools {
+		if fc != nil && !allowed(fc.IncludePools, fc.ExcludePools, poolName) {
+			continue
+		}
+		stats, _, err := poolStatsWithTimeout(poolName)
+		if err != nil {
+			log.Printf("zfs_exporter: collecting pool %q failed: %v", poolName, err)
+			continue
+		}
+		collectOnePool(poolName, stats, fc, emit, emitHisto)
+	}
+	return nil
+}
+
+// poolCollectErrorCounts tracks zfs_pool_collect_errors_total across
+// scrapes: it's a counter, so (unlike everything else collectOnePool
+// reports) it has to accumulate outside any single Collect call.
+var poolCollectErrorCounts sync.Map // poolName string -> *uint64
+
+func bumpPoolCollectErrors(poolName string) uint64 {
+	v, _ := poolCollectErrorCounts.LoadOrStore(poolName, new(uint64))
+	return atomic.AddUint64(v.(*uint64), 1)
+}
+
 func (c *zfsCollector) Collect(ch chan<- prometheus.Metric) {
+	fc := currentFilterConfig()
 	pools, err := ioctl.PoolConfigs()
 	if err != nil {
-		panic(err)
+		log.Printf("zfs_exporter: ioctl.PoolConfigs failed, skipping this scrape: %v", err)
+		return
 	}
+
+	var wg sync.WaitGroup
 	for poolName := range pools {
+		wg.Add(1)
+		go func(poolName string) {
+			defer wg.Done()
+			collectPoolWithTimeout(poolName, fc, ch)
+		}(poolName)
+	}
+	wg.Wait()
+}
+
+// poolStatsWithTimeout calls ioctl.PoolStats for poolName under a
+// -collect-timeout deadline, instead of the unbounded call that used
+// to be able to hang (or a failure that used to panic) a caller.
+// ioctl.PoolStats doesn't take a context, so a timed-out call can't
+// actually be cancelled; we just stop waiting on it and return an
+// error, and the abandoned goroutine finishes (or doesn't) on its
+// own. elapsed is returned alongside so callers that report
+// zfs_scrape_duration_seconds don't need their own timer.
+func poolStatsWithTimeout(poolName string) (stats map[string]interface{}, elapsed time.Duration, err error) {
+	start := time.Now()
+	type result struct {
+		stats map[string]interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
 		stats, err := ioctl.PoolStats(poolName)
-		if err != nil {
-			panic(err)
-		}
-
-		// TODO: should the number of children be reported as
-		// a separate metric? Should we report the import
-		// time?
-		// children := stats["vdev_children"].(uint64)
-		guid := strconv.FormatUint(stats["pool_guid"].(uint64), 10)
-		ltimes := stats["initial_load_time"].([]uint64)
-		ch <- prometheus.MustNewConstMetric(poolLoadTime, prometheus.GaugeValue, float64(ltimes[0]), poolName, guid)
-		ch <- prometheus.MustNewConstMetric(poolErrors, prometheus.GaugeValue, float64(stats["error_count"].(uint64)), poolName, guid)
-		ch <- prometheus.MustNewConstMetric(poolChildren, prometheus.GaugeValue, float64(stats["vdev_children"].(uint64)), poolName, guid)
-		ch <- prometheus.MustNewConstMetric(poolConfigTxg, prometheus.GaugeValue, float64(stats["txg"].(uint64)), poolName)
-
-		vdevTree := stats["vdev_tree"].(map[string]interface{})
-		vdevs := vdevTree["children"].([]map[string]interface{})
-		reportVdevStats(poolName, "root", vdevTree, ch)
-		if *vdevDepth > 0 {
-			for _, vdev := range vdevs {
-				vdn := vdevName("", vdev)
-				reportVdevStats(poolName, vdn, vdev, ch)
-				if *vdevDepth > 1 {
-					descendVdev(poolName, vdn, vdev, ch)
-				}
-			}
+		done <- result{stats, err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(*collectTimeout):
+		res = result{err: fmt.Errorf("timed out after %s", *collectTimeout)}
+	}
+	return res.stats, time.Since(start), res.err
+}
+
+// collectPoolWithTimeout collects and reports one pool's stats with a
+// -collect-timeout deadline (see poolStatsWithTimeout). This is the
+// Go-idiomatic analogue of isolating each pool's collection the way a
+// separate process would.
+func collectPoolWithTimeout(poolName string, fc *filterConfig, ch chan<- prometheus.Metric) {
+	// zfs_scrape_duration_seconds/zfs_pool_up/zfs_pool_collect_errors_total
+	// aren't walked out of the vdev tree like everything else
+	// collectOnePool reports, so they don't go through emit/emitHisto;
+	// gate them on the same include/exclude_pools check here so an
+	// excluded pool doesn't keep showing up under these metric names.
+	if fc != nil && !allowed(fc.IncludePools, fc.ExcludePools, poolName) {
+		return
+	}
+
+	stats, elapsed, err := poolStatsWithTimeout(poolName)
+
+	ch <- prometheus.MustNewConstMetric(scrapeDuration, prometheus.GaugeValue, elapsed.Seconds(), poolName)
+
+	if err != nil {
+		log.Printf("zfs_exporter: collecting pool %q failed: %v", poolName, err)
+		ch <- prometheus.MustNewConstMetric(poolUp, prometheus.GaugeValue, 0, poolName)
+		ch <- prometheus.MustNewConstMetric(poolCollectErrors, prometheus.CounterValue, float64(bumpPoolCollectErrors(poolName)), poolName)
+		return
+	}
+
+	emit := func(r metricRecord) {
+		if !fc.allowRecord(r) {
+			return
+		}
+		for _, fv := range r.fields {
+			ch <- prometheus.MustNewConstMetric(fv.desc, fv.vtype, fv.value, r.labelValues...)
+		}
+	}
+	emitHisto := func(h histoRecord) {
+		if !fc.allowRecord(metricRecord{tags: h.tags}) {
+			return
 		}
+		ch <- prometheus.MustNewConstHistogram(h.desc, h.count, h.sum, h.buckets, h.labelValues...)
+	}
+	collectOnePool(poolName, stats, fc, emit, emitHisto)
+	ch <- prometheus.MustNewConstMetric(poolUp, prometheus.GaugeValue, 1, poolName)
+}
 
-		// Report pool scan stats.
-		if vdevTree["scan_stats"] != nil {
-			rawStats := vdevTree["scan_stats"].([]uint64)
-			for i, s := range scanStats {
-				if i >= len(rawStats) {
-					break
-				}
-				// We know for sure that these are all gauges.
-				ch <- prometheus.MustNewConstMetric(s.desc, prometheus.GaugeValue, float64(rawStats[i]), poolName)
+// influxLine renders one metricRecord as an InfluxDB line-protocol
+// line: "measurement,tag=value,... field=value,...i timestamp". Most
+// of ZFS's counters and gauges are integers and get an "i" suffix
+// rather than being written as an InfluxDB float; fields marked
+// fractional (the seconds-valued latency/duration gauges) are written
+// as plain floats instead, since truncating them to int64 rounds
+// essentially all real latencies down to 0.
+func influxLine(r metricRecord, ts int64) string {
+	var b strings.Builder
+	b.WriteString(r.measurement)
+
+	tagKeys := make([]string, 0, len(r.tags))
+	for k, v := range r.tags {
+		if v == "" {
+			continue
+		}
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+	for _, k := range tagKeys {
+		fmt.Fprintf(&b, ",%s=%s", k, r.tags[k])
+	}
+
+	fieldKeys := make([]string, 0, len(r.fields))
+	for k := range r.fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+	b.WriteByte(' ')
+	for i, k := range fieldKeys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if r.fields[k].fractional {
+			fmt.Fprintf(&b, "%s=%g", k, r.fields[k].value)
+		} else {
+			fmt.Fprintf(&b, "%s=%di", k, int64(r.fields[k].value))
+		}
+	}
+	fmt.Fprintf(&b, " %d", ts)
+	return b.String()
+}
+
+// secondsHistograms are the extended-stat histogram families whose
+// sum is a weighted-mean-derived seconds value (see reportVdevStats'
+// divisor handling); every other histogram (the IO-size ones) sums
+// raw byte counts instead, so its sum stays an integer field.
+var secondsHistograms = map[*prometheus.Desc]bool{
+	zioLatencyTotal: true,
+	zioLatencyDisk:  true,
+	queueLatency:    true,
+}
+
+// influxHistoLine renders a histoRecord. Raw 37-bucket ZFS latency
+// histograms aren't directly representable as a single line-protocol
+// line, so we only emit the derived count and midpoint-weighted sum,
+// not the individual buckets. With -sum-histogram-buckets the latency
+// histograms never get here; reportVdevStats emits their coarse
+// bucket counts as plain records instead.
+func influxHistoLine(h histoRecord, ts int64) string {
+	rec := metricRecord{
+		measurement: h.measurement,
+		tags:        h.tags,
+		fields: map[string]fieldValue{
+			"count": {value: float64(h.count)},
+			"sum":   {value: h.sum, fractional: secondsHistograms[h.desc]},
+		},
+	}
+	return influxLine(rec, ts)
+}
+
+// runInfluxDBCycle runs one full collection pass and writes it to w
+// as InfluxDB line protocol, one line per metricRecord/histoRecord.
+func runInfluxDBCycle(w *bufio.Writer) error {
+	fc := currentFilterConfig()
+	ts := time.Now().Unix()
+	var lines []string
+	emit := func(r metricRecord) {
+		if len(r.fields) == 0 || !fc.allowRecord(r) {
+			return
+		}
+		lines = append(lines, influxLine(fc.relabel(r), ts))
+	}
+	emitHisto := func(h histoRecord) {
+		if !fc.allowRecord(metricRecord{tags: h.tags}) {
+			return
+		}
+		h.tags = fc.relabel(metricRecord{tags: h.tags}).tags
+		lines = append(lines, influxHistoLine(h, ts))
+	}
+	if err := collectPools(fc, emit, emitHisto); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if _, err := w.WriteString(l + "\n"); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// runInfluxDB drives InfluxDB line-protocol output on stdout instead
+// of serving Prometheus's HTTP exposition, so that Telegraf can run
+// this exporter as an inputs.exec/inputs.execd command. In "exec"
+// mode it collects once and returns. In "execd" mode it collects once
+// per newline read from stdin and keeps going until stdin is closed,
+// matching how Telegraf drives long-running execd commands.
+func runInfluxDB() error {
+	w := bufio.NewWriter(os.Stdout)
+	if *influxMode == "execd" {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if err := runInfluxDBCycle(w); err != nil {
+				return err
 			}
 		}
+		return scanner.Err()
 	}
+	return runInfluxDBCycle(w)
 }
 
 func main() {
@@ -440,11 +1255,25 @@ func main() {
 	if err != nil {
 		log.Fatalf("ioctl.Init failed: %v", err)
 	}
+	flag.Parse()
+
+	fc, err := loadFilterConfig(*configPath)
+	if err != nil {
+		log.Fatalf("loading -config %s: %v", *configPath, err)
+	}
+	currentFilter.Store(fc)
+	watchFilterConfigReload(*configPath)
+
+	if *format == "influxdb" {
+		if err := runInfluxDB(); err != nil {
+			log.Fatalf("influxdb output failed: %v", err)
+		}
+		return
+	}
 
 	c := zfsCollector{}
 	prometheus.MustRegister(&c)
 
-	flag.Parse()
 	http.Handle("/metrics", promhttp.Handler())
 	if err := http.ListenAndServe(*listenAddr, nil); err != nil {
 		log.Fatalf("failed to listen: %v", err)